@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/labels"
+
+	argoappv1 "github.com/argoproj/argo-cd/pkg/apis/application/v1alpha1"
+	applister "github.com/argoproj/argo-cd/pkg/client/listers/application/v1alpha1"
+)
+
+// fakeAppLister is a minimal applister.ApplicationLister backed by an in-memory map, so
+// controller tests don't need a real informer cache.
+type fakeAppLister struct {
+	apps map[string]*argoappv1.Application
+}
+
+func newFakeAppLister(apps ...*argoappv1.Application) *fakeAppLister {
+	l := &fakeAppLister{apps: make(map[string]*argoappv1.Application)}
+	for _, app := range apps {
+		l.apps[app.Namespace+"/"+app.Name] = app
+	}
+	return l
+}
+
+func (l *fakeAppLister) List(selector labels.Selector) ([]*argoappv1.Application, error) {
+	out := make([]*argoappv1.Application, 0, len(l.apps))
+	for _, app := range l.apps {
+		out = append(out, app)
+	}
+	return out, nil
+}
+
+func (l *fakeAppLister) Applications(namespace string) applister.ApplicationNamespaceLister {
+	return fakeAppNamespaceLister{lister: l, namespace: namespace}
+}
+
+type fakeAppNamespaceLister struct {
+	lister    *fakeAppLister
+	namespace string
+}
+
+func (l fakeAppNamespaceLister) List(selector labels.Selector) ([]*argoappv1.Application, error) {
+	out := make([]*argoappv1.Application, 0)
+	for _, app := range l.lister.apps {
+		if app.Namespace == l.namespace {
+			out = append(out, app)
+		}
+	}
+	return out, nil
+}
+
+func (l fakeAppNamespaceLister) Get(name string) (*argoappv1.Application, error) {
+	app, ok := l.lister.apps[l.namespace+"/"+name]
+	if !ok {
+		return nil, fmt.Errorf("application %s/%s not found", l.namespace, name)
+	}
+	return app, nil
+}
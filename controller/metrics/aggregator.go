@@ -0,0 +1,213 @@
+package metrics
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Argocd-cm keys controlling the opt-in sliding-window aggregation layer.
+const (
+	// AggregationEnabledKey toggles the sliding-window aggregator on or off.
+	AggregationEnabledKey = "metrics.aggregation.enabled"
+	// AggregationBucketNumKey sets how many buckets make up the sliding window.
+	AggregationBucketNumKey = "metrics.aggregation.bucket.num"
+	// AggregationTimeWindowSecondsKey sets the total width, in seconds, of the sliding window.
+	AggregationTimeWindowSecondsKey = "metrics.aggregation.time.window.seconds"
+)
+
+// AggregationConfig controls the sliding-window aggregator registered by EnableAggregation.
+type AggregationConfig struct {
+	// BucketNum is the number of buckets covering the window. Defaults to 10.
+	BucketNum int
+	// TimeWindowSeconds is the total width of the sliding window, in seconds. Defaults to 60.
+	TimeWindowSeconds int
+}
+
+func (c AggregationConfig) withDefaults() AggregationConfig {
+	if c.BucketNum <= 0 {
+		c.BucketNum = 10
+	}
+	if c.TimeWindowSeconds <= 0 {
+		c.TimeWindowSeconds = 60
+	}
+	return c
+}
+
+// aggBucket holds the reconcile latency samples and k8s request counts observed during one
+// slice of the sliding window, keyed by the "namespace/name/project" label tuple.
+type aggBucket struct {
+	latencies map[string][]float64
+	requests  map[string]int64
+}
+
+func newAggBucket() *aggBucket {
+	return &aggBucket{
+		latencies: make(map[string][]float64),
+		requests:  make(map[string]int64),
+	}
+}
+
+// slidingAggregator maintains a ring of aggBuckets covering AggregationConfig.TimeWindowSeconds,
+// advancing to the next bucket (clearing it in place) every bucketDuration. It implements
+// prometheus.Collector so it can be scraped alongside the other app metrics.
+type slidingAggregator struct {
+	mu             sync.Mutex
+	buckets        []*aggBucket
+	current        int
+	bucketDuration time.Duration
+	windowSeconds  float64
+}
+
+func newSlidingAggregator(cfg AggregationConfig) *slidingAggregator {
+	cfg = cfg.withDefaults()
+	buckets := make([]*aggBucket, cfg.BucketNum)
+	for i := range buckets {
+		buckets[i] = newAggBucket()
+	}
+	return &slidingAggregator{
+		buckets:        buckets,
+		bucketDuration: time.Duration(cfg.TimeWindowSeconds) * time.Second / time.Duration(cfg.BucketNum),
+		windowSeconds:  float64(cfg.TimeWindowSeconds),
+	}
+}
+
+// Run advances the bucket pointer on a ticker until ctx is done, evicting the bucket that
+// becomes current so label sets not seen within a full window are dropped.
+func (a *slidingAggregator) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.bucketDuration)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			a.mu.Lock()
+			a.current = (a.current + 1) % len(a.buckets)
+			a.buckets[a.current] = newAggBucket()
+			a.mu.Unlock()
+		}
+	}
+}
+
+func labelKey(namespace, name, project string) string {
+	return strings.Join([]string{namespace, name, project}, "/")
+}
+
+func (a *slidingAggregator) observeReconcile(namespace, name, project string, seconds float64) {
+	key := labelKey(namespace, name, project)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	b := a.buckets[a.current]
+	b.latencies[key] = append(b.latencies[key], seconds)
+}
+
+func (a *slidingAggregator) observeK8sRequest(namespace, name, project string) {
+	key := labelKey(namespace, name, project)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.buckets[a.current].requests[key]++
+}
+
+// evict drops every sample recorded for namespace/name/project from all buckets, keeping the
+// aggregator's label set in lockstep with the cardinality guard it is registered behind.
+func (a *slidingAggregator) evict(namespace, name, project string) {
+	key := labelKey(namespace, name, project)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, b := range a.buckets {
+		delete(b.latencies, key)
+		delete(b.requests, key)
+	}
+}
+
+var (
+	descReconcileQuantile = prometheus.NewDesc(
+		"argocd_app_reconcile_quantile_seconds",
+		"Reconcile duration quantile over a sliding window.",
+		append(descAppDefaultLabels, "quantile", "window"),
+		nil,
+	)
+	descK8sRequestQPS = prometheus.NewDesc(
+		"argocd_app_k8s_request_qps",
+		"Kubernetes request rate over a sliding window.",
+		append(descAppDefaultLabels, "window"),
+		nil,
+	)
+)
+
+// Describe implements the prometheus.Collector interface
+func (a *slidingAggregator) Describe(ch chan<- *prometheus.Desc) {
+	ch <- descReconcileQuantile
+	ch <- descK8sRequestQPS
+}
+
+// Collect implements the prometheus.Collector interface, computing p50/p95/p99 reconcile
+// latencies and k8s request QPS across the currently-valid buckets for every label set seen
+// within the window.
+func (a *slidingAggregator) Collect(ch chan<- prometheus.Metric) {
+	window := strconv.Itoa(int(a.windowSeconds)) + "s"
+
+	a.mu.Lock()
+	latencies := make(map[string][]float64)
+	requests := make(map[string]int64)
+	for _, b := range a.buckets {
+		for key, samples := range b.latencies {
+			latencies[key] = append(latencies[key], samples...)
+		}
+		for key, count := range b.requests {
+			requests[key] += count
+		}
+	}
+	a.mu.Unlock()
+
+	for key, samples := range latencies {
+		namespace, name, project := splitLabelKey(key)
+		sorted := append([]float64(nil), samples...)
+		sort.Float64s(sorted)
+		for _, q := range []struct {
+			label string
+			p     float64
+		}{{"0.5", 0.5}, {"0.95", 0.95}, {"0.99", 0.99}} {
+			v := quantile(sorted, q.p)
+			ch <- prometheus.MustNewConstMetric(descReconcileQuantile, prometheus.GaugeValue, v, namespace, name, project, q.label, window)
+		}
+	}
+	for key, count := range requests {
+		namespace, name, project := splitLabelKey(key)
+		qps := float64(count) / a.windowSeconds
+		ch <- prometheus.MustNewConstMetric(descK8sRequestQPS, prometheus.GaugeValue, qps, namespace, name, project, window)
+	}
+}
+
+func splitLabelKey(key string) (namespace, name, project string) {
+	parts := strings.SplitN(key, "/", 3)
+	if len(parts) != 3 {
+		return "", "", ""
+	}
+	return parts[0], parts[1], parts[2]
+}
+
+// quantile returns the value at percentile p (0-1) of an already-sorted slice.
+func quantile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(p * float64(len(sorted)-1))
+	return sorted[idx]
+}
+
+// EnableAggregation registers a sliding-window aggregator over the reconcile and k8s request
+// metrics, exposing derived argocd_app_reconcile_quantile_seconds and argocd_app_k8s_request_qps
+// gauges. It is opt-in: callers typically gate this on AggregationEnabledKey from argocd-cm.
+func (m *MetricsServer) EnableAggregation(ctx context.Context, cfg AggregationConfig) {
+	agg := newSlidingAggregator(cfg)
+	m.aggregator = agg
+	go agg.Run(ctx)
+	m.registry.MustRegister(agg)
+}
@@ -0,0 +1,42 @@
+package metrics
+
+import "testing"
+
+func TestQuantile(t *testing.T) {
+	tests := []struct {
+		name   string
+		sorted []float64
+		p      float64
+		want   float64
+	}{
+		{"empty", nil, 0.5, 0},
+		{"single value", []float64{3}, 0.99, 3},
+		{"p0 returns minimum", []float64{1, 2, 3, 4, 5}, 0, 1},
+		{"p1 returns maximum", []float64{1, 2, 3, 4, 5}, 1, 5},
+		{"p50 of five values", []float64{1, 2, 3, 4, 5}, 0.5, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := quantile(tt.sorted, tt.p); got != tt.want {
+				t.Errorf("quantile(%v, %v) = %v, want %v", tt.sorted, tt.p, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlidingAggregatorEvictRemovesAllSamplesForKey(t *testing.T) {
+	agg := newSlidingAggregator(AggregationConfig{BucketNum: 2, TimeWindowSeconds: 2})
+	agg.observeReconcile("ns", "app", "default", 1.5)
+	agg.observeK8sRequest("ns", "app", "default")
+
+	agg.evict("ns", "app", "default")
+
+	for _, b := range agg.buckets {
+		if _, ok := b.latencies[labelKey("ns", "app", "default")]; ok {
+			t.Errorf("expected evict to remove latency samples")
+		}
+		if _, ok := b.requests[labelKey("ns", "app", "default")]; ok {
+			t.Errorf("expected evict to remove request samples")
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package metrics
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Argocd-cm keys controlling the cardinality guard.
+const (
+	// MaxSeriesKey caps the number of distinct label tuples tracked across the per-app
+	// metrics listed on CardinalityConfig.
+	MaxSeriesKey = "metrics.max_series"
+	// AllowedLabelsKey, when set, restricts descAppDefaultLabels to the listed subset.
+	AllowedLabelsKey = "metrics.labels.allow"
+	// DeniedLabelsKey, when set, removes the listed subset from descAppDefaultLabels.
+	DeniedLabelsKey = "metrics.labels.deny"
+)
+
+// CardinalityConfig bounds the label cardinality of per-application metrics. Setting both
+// AllowedLabels and DeniedLabels is not supported; AllowedLabels takes precedence. MaxSeries,
+// when positive, caps the number of distinct projected label tuples tracked across
+// descAppInfo, descAppSyncStatusCode, descAppHealthStatus, syncCounter, reconcileHistogram,
+// reconcilePhaseHistogram, queueLatencyHistogram and k8sRequestCounter, evicting the
+// least-recently-seen tuple to make room for a new one and incrementing
+// argocd_metrics_series_dropped_total. The sliding-window aggregator, when enabled, drops an
+// application's samples whenever the guard evicts a reconcileHistogram/k8sRequestCounter series
+// first admitted for it. The aggregator keys samples by the full namespace/name/project and
+// ignores AllowedLabels/DeniedLabels, so once those merge several applications into one tuple
+// its label sets are not bounded by MaxSeries.
+type CardinalityConfig struct {
+	MaxSeries     int
+	AllowedLabels []string
+	DeniedLabels  []string
+}
+
+// withLabels returns a freshly allocated slice of base followed by extra, so that repeated
+// calls sharing the same base never alias one another's backing array.
+func withLabels(base []string, extra ...string) []string {
+	out := make([]string, 0, len(base)+len(extra))
+	out = append(out, base...)
+	return append(out, extra...)
+}
+
+func containsLabel(labels []string, l string) bool {
+	for _, v := range labels {
+		if v == l {
+			return true
+		}
+	}
+	return false
+}
+
+// projectedLabels returns the subset of descAppDefaultLabels retained by this config.
+func (c CardinalityConfig) projectedLabels() []string {
+	kept := make([]string, 0, len(descAppDefaultLabels))
+	for _, l := range descAppDefaultLabels {
+		if len(c.AllowedLabels) > 0 && !containsLabel(c.AllowedLabels, l) {
+			continue
+		}
+		if len(c.AllowedLabels) == 0 && containsLabel(c.DeniedLabels, l) {
+			continue
+		}
+		kept = append(kept, l)
+	}
+	return kept
+}
+
+// projectValues drops the values at the positions projectedLabels removes from
+// descAppDefaultLabels, preserving relative order. values must be namespace, name, project.
+func (c CardinalityConfig) projectValues(values []string) []string {
+	kept := make([]string, 0, len(values))
+	for i, l := range descAppDefaultLabels {
+		if i >= len(values) {
+			break
+		}
+		if len(c.AllowedLabels) > 0 && !containsLabel(c.AllowedLabels, l) {
+			continue
+		}
+		if len(c.AllowedLabels) == 0 && containsLabel(c.DeniedLabels, l) {
+			continue
+		}
+		kept = append(kept, values[i])
+	}
+	return kept
+}
+
+type seriesEntry struct {
+	key   string
+	evict func()
+}
+
+// cardinalityGuard is an LRU over projected label tuples shared by the app collector and the
+// sync/reconcile/k8s-request metric recorders, so that argocd_metrics_series_dropped_total
+// reflects evictions across all of them uniformly.
+type cardinalityGuard struct {
+	mu        sync.Mutex
+	maxSeries int
+	order     *list.List
+	index     map[string]*list.Element
+	dropped   prometheus.Counter
+}
+
+func newCardinalityGuard(maxSeries int, dropped prometheus.Counter) *cardinalityGuard {
+	return &cardinalityGuard{
+		maxSeries: maxSeries,
+		order:     list.New(),
+		index:     make(map[string]*list.Element),
+		dropped:   dropped,
+	}
+}
+
+func seriesKey(metric string, values []string) string {
+	h := sha256.New()
+	h.Write([]byte(metric))
+	h.Write([]byte(strings.Join(values, "\x00")))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// admit records key as seen, evicting the least-recently-seen key (and invoking its evict
+// callback) if doing so is required to stay within maxSeries. A zero or negative maxSeries
+// disables the guard entirely.
+func (g *cardinalityGuard) admit(key string, evict func()) {
+	if g.maxSeries <= 0 {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if el, ok := g.index[key]; ok {
+		g.order.MoveToFront(el)
+		return
+	}
+	if g.order.Len() >= g.maxSeries {
+		oldest := g.order.Back()
+		if oldest != nil {
+			entry := oldest.Value.(*seriesEntry)
+			g.order.Remove(oldest)
+			delete(g.index, entry.key)
+			if entry.evict != nil {
+				entry.evict()
+			}
+			g.dropped.Inc()
+		}
+	}
+	g.index[key] = g.order.PushFront(&seriesEntry{key: key, evict: evict})
+}
+
+// isTracked reports whether key is currently within the guard's live set. Always true when
+// the guard is disabled.
+func (g *cardinalityGuard) isTracked(key string) bool {
+	if g.maxSeries <= 0 {
+		return true
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	_, ok := g.index[key]
+	return ok
+}
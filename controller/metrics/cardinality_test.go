@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func newTestGuard(maxSeries int) (*cardinalityGuard, prometheus.Counter) {
+	dropped := prometheus.NewCounter(prometheus.CounterOpts{Name: "test_dropped_total"})
+	return newCardinalityGuard(maxSeries, dropped), dropped
+}
+
+func TestCardinalityGuardAdmitEvictsLeastRecentlySeen(t *testing.T) {
+	guard, _ := newTestGuard(2)
+
+	var evicted []string
+	evict := func(key string) func() {
+		return func() { evicted = append(evicted, key) }
+	}
+
+	guard.admit("a", evict("a"))
+	guard.admit("b", evict("b"))
+	if !guard.isTracked("a") || !guard.isTracked("b") {
+		t.Fatalf("expected a and b to be tracked, got evicted=%v", evicted)
+	}
+
+	// Touching "a" again should move it to the front, so "b" is evicted instead when "c"
+	// is admitted.
+	guard.admit("a", evict("a"))
+	guard.admit("c", evict("c"))
+
+	if !guard.isTracked("a") {
+		t.Errorf("expected a to remain tracked after being re-admitted")
+	}
+	if guard.isTracked("b") {
+		t.Errorf("expected b to be evicted")
+	}
+	if !guard.isTracked("c") {
+		t.Errorf("expected c to be tracked")
+	}
+	if len(evicted) != 1 || evicted[0] != "b" {
+		t.Errorf("expected only b's evict callback to run, got %v", evicted)
+	}
+}
+
+func TestCardinalityGuardDisabledWhenMaxSeriesNotPositive(t *testing.T) {
+	guard, _ := newTestGuard(0)
+
+	called := false
+	guard.admit("a", func() { called = true })
+	guard.admit("b", func() { called = true })
+
+	if !guard.isTracked("a") || !guard.isTracked("b") {
+		t.Errorf("expected a disabled guard to track everything")
+	}
+	if called {
+		t.Errorf("expected a disabled guard to never evict")
+	}
+}
+
+func TestCardinalityGuardIncrementsDroppedCounter(t *testing.T) {
+	guard, dropped := newTestGuard(1)
+
+	guard.admit("a", func() {})
+	guard.admit("b", func() {})
+
+	if got := testCounterValue(dropped); got != 1 {
+		t.Errorf("expected dropped counter to be 1, got %v", got)
+	}
+}
+
+func testCounterValue(c prometheus.Counter) float64 {
+	var m dto.Metric
+	_ = c.Write(&m)
+	return m.GetCounter().GetValue()
+}
@@ -0,0 +1,192 @@
+package metrics
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/client-go/rest"
+	clientmetrics "k8s.io/client-go/tools/metrics"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// workqueueMetricsProvider implements workqueue.MetricsProvider so that every workqueue
+// started by the application controller (and, via RegisterWorkqueue, any other named
+// workqueue) reports depth, adds, latency, work duration, unfinished work age and retries
+// on the same registry as the rest of the app metrics.
+type workqueueMetricsProvider struct {
+	depth          *prometheus.GaugeVec
+	adds           *prometheus.CounterVec
+	latency        *prometheus.HistogramVec
+	workDuration   *prometheus.HistogramVec
+	unfinishedWork *prometheus.GaugeVec
+	longestRunning *prometheus.GaugeVec
+	retries        *prometheus.CounterVec
+}
+
+func newWorkqueueMetricsProvider() *workqueueMetricsProvider {
+	return &workqueueMetricsProvider{
+		depth: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "argocd_workqueue_depth",
+			Help: "Current depth of the named workqueue.",
+		}, []string{"name"}),
+		adds: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "argocd_workqueue_adds_total",
+			Help: "Total number of items added to the named workqueue.",
+		}, []string{"name"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "argocd_workqueue_queue_latency_seconds",
+			Help:    "How long an item stays in the named workqueue before it is processed.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"name"}),
+		workDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "argocd_workqueue_work_duration_seconds",
+			Help:    "How long it takes to process an item from the named workqueue.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"name"}),
+		unfinishedWork: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "argocd_workqueue_unfinished_work_seconds",
+			Help: "How long the oldest in-flight item from the named workqueue has been processing.",
+		}, []string{"name"}),
+		longestRunning: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "argocd_workqueue_longest_running_processor_seconds",
+			Help: "How long the longest-running processor from the named workqueue has been running.",
+		}, []string{"name"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "argocd_workqueue_retries_total",
+			Help: "Total number of retries handled by the named workqueue.",
+		}, []string{"name"}),
+	}
+}
+
+func (p *workqueueMetricsProvider) register(registry *prometheus.Registry) {
+	registry.MustRegister(p.depth, p.adds, p.latency, p.workDuration, p.unfinishedWork, p.longestRunning, p.retries)
+}
+
+func (p *workqueueMetricsProvider) NewDepthMetric(name string) workqueue.GaugeMetric {
+	return p.depth.WithLabelValues(name)
+}
+
+func (p *workqueueMetricsProvider) NewAddsMetric(name string) workqueue.CounterMetric {
+	return p.adds.WithLabelValues(name)
+}
+
+func (p *workqueueMetricsProvider) NewLatencyMetric(name string) workqueue.HistogramMetric {
+	return p.latency.WithLabelValues(name)
+}
+
+func (p *workqueueMetricsProvider) NewWorkDurationMetric(name string) workqueue.HistogramMetric {
+	return p.workDuration.WithLabelValues(name)
+}
+
+func (p *workqueueMetricsProvider) NewUnfinishedWorkSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return p.unfinishedWork.WithLabelValues(name)
+}
+
+func (p *workqueueMetricsProvider) NewLongestRunningProcessorSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return p.longestRunning.WithLabelValues(name)
+}
+
+func (p *workqueueMetricsProvider) NewRetriesMetric(name string) workqueue.CounterMetric {
+	return p.retries.WithLabelValues(name)
+}
+
+// clusterRESTMetrics implements the client-go tools/metrics latency and result hooks, keeping
+// request counts and latencies broken down by destination cluster host (the host[:port] of
+// its server URL, as client-go reports it) so that bulk background list/watch traffic
+// against a specific cluster is visible.
+type clusterRESTMetrics struct {
+	latency *prometheus.HistogramVec
+	result  *prometheus.CounterVec
+}
+
+func newClusterRESTMetrics() *clusterRESTMetrics {
+	return &clusterRESTMetrics{
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "argocd_cluster_rest_request_duration_seconds",
+			Help:    "Client-go REST request latency against a destination cluster.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"server", "verb"}),
+		result: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "argocd_cluster_rest_request_total",
+			Help: "Client-go REST requests against a destination cluster.",
+		}, []string{"server", "code", "method"}),
+	}
+}
+
+func (m *clusterRESTMetrics) register(registry *prometheus.Registry) {
+	registry.MustRegister(m.latency, m.result)
+}
+
+func (m *clusterRESTMetrics) Observe(_ context.Context, verb string, u url.URL, latency time.Duration) {
+	m.latency.WithLabelValues(u.Host, verb).Observe(latency.Seconds())
+}
+
+func (m *clusterRESTMetrics) Increment(_ context.Context, code string, method string, host string) {
+	m.result.WithLabelValues(host, code, method).Inc()
+}
+
+// sharedWorkqueueProvider is the one workqueue.MetricsProvider installed in client-go for this
+// process. workqueue.SetProvider only takes effect on its first call, so every MetricsServer
+// registers this same provider rather than one of its own.
+var (
+	sharedWorkqueueProviderOnce sync.Once
+	sharedWorkqueueProvider     *workqueueMetricsProvider
+)
+
+// RegisterWorkqueue registers the process-wide workqueue.MetricsProvider on this
+// MetricsServer's registry (installing it in client-go on the first call in the process) and
+// pre-touches every series for name so argocd_workqueue_* appears with zero values before the
+// queue processes anything. m.workqueueOnce keeps a single instance from registering the
+// provider on its registry twice.
+//
+// Callers must call RegisterWorkqueue before constructing any workqueue it should cover:
+// client-go snapshots the current provider when the queue is built, so registering afterwards
+// leaves that queue permanently wired to whatever provider (typically the no-op default) was in
+// effect at construction time.
+func (m *MetricsServer) RegisterWorkqueue(name string) {
+	m.workqueueOnce.Do(func() {
+		sharedWorkqueueProviderOnce.Do(func() {
+			sharedWorkqueueProvider = newWorkqueueMetricsProvider()
+			workqueue.SetProvider(sharedWorkqueueProvider)
+		})
+		sharedWorkqueueProvider.register(m.registry)
+		m.workqueueProvider = sharedWorkqueueProvider
+	})
+	p := m.workqueueProvider
+	p.NewDepthMetric(name)
+	p.NewAddsMetric(name)
+	p.NewLatencyMetric(name)
+	p.NewWorkDurationMetric(name)
+	p.NewUnfinishedWorkSecondsMetric(name)
+	p.NewLongestRunningProcessorSecondsMetric(name)
+	p.NewRetriesMetric(name)
+}
+
+// RegisterClusterRESTClient installs this MetricsServer's client-go REST latency/result hooks
+// (on the first call to this instance, since client-go passes the destination host at call
+// time) and pre-touches a series for the host of server so it appears before the first request.
+//
+// clientmetrics.Register is a process-global hook in client-go that only takes effect on its
+// first call, so the hooks of a second MetricsServer in the same process are registered on its
+// registry but never receive requests; m.clusterRESTOnce only prevents a single instance from
+// registering twice.
+func (m *MetricsServer) RegisterClusterRESTClient(server string, cfg *rest.Config) {
+	m.clusterRESTOnce.Do(func() {
+		metrics := newClusterRESTMetrics()
+		metrics.register(m.registry)
+		clientmetrics.Register(clientmetrics.RegisterOpts{
+			RequestLatency: metrics,
+			RequestResult:  metrics,
+		})
+		m.clusterRESTMetrics = metrics
+	})
+	_ = cfg
+	host := server
+	if u, err := url.Parse(server); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	m.clusterRESTMetrics.result.WithLabelValues(host, "", "")
+}
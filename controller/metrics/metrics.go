@@ -3,6 +3,8 @@ package metrics
 import (
 	"context"
 	"net/http"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -24,7 +26,37 @@ type MetricsServer struct {
 	k8sRequestCounter       *prometheus.CounterVec
 	clusterEventsCounter    *prometheus.CounterVec
 	reconcileHistogram      *prometheus.HistogramVec
+	reconcilePhaseHistogram *prometheus.HistogramVec
+	queueLatencyHistogram   *prometheus.HistogramVec
 	registry                *prometheus.Registry
+	aggregator              *slidingAggregator
+	cardinality             CardinalityConfig
+	guard                   *cardinalityGuard
+	workqueueProvider       *workqueueMetricsProvider
+	workqueueOnce           sync.Once
+	clusterRESTMetrics      *clusterRESTMetrics
+	clusterRESTOnce         sync.Once
+}
+
+// reconcilePhase identifies a stage of the application reconciliation pipeline that is
+// timed separately from the overall argocd_app_reconcile duration.
+type reconcilePhase string
+
+const (
+	ReconcilePhaseGitFetch    reconcilePhase = "git_fetch"
+	ReconcilePhaseManifestGen reconcilePhase = "manifest_generation"
+	ReconcilePhaseDiff        reconcilePhase = "diff"
+	ReconcilePhaseSyncWait    reconcilePhase = "sync_wait"
+)
+
+// ReconcileTimer tracks the phase-by-phase progress of a single application reconciliation.
+// Each ObservePhase call records into the reconcile phase histogram immediately; Finish
+// records the overall duration into the reconcile histogram.
+type ReconcileTimer struct {
+	metrics        *MetricsServer
+	app            *argoappv1.Application
+	started        time.Time
+	lastCheckpoint time.Time
 }
 
 const (
@@ -34,45 +66,41 @@ const (
 
 // Follow Prometheus naming practices
 // https://prometheus.io/docs/practices/naming/
-var (
-	descAppDefaultLabels = []string{"namespace", "name", "project"}
-
-	descAppInfo = prometheus.NewDesc(
-		"argocd_app_info",
-		"Information about application.",
-		append(descAppDefaultLabels, "repo", "dest_server", "dest_namespace"),
-		nil,
-	)
-	descAppCreated = prometheus.NewDesc(
-		"argocd_app_created_time",
-		"Creation time in unix timestamp for an application.",
-		descAppDefaultLabels,
-		nil,
-	)
-	descAppSyncStatusCode = prometheus.NewDesc(
-		"argocd_app_sync_status",
-		"The application current sync status.",
-		append(descAppDefaultLabels, "sync_status"),
-		nil,
-	)
-	descAppHealthStatus = prometheus.NewDesc(
-		"argocd_app_health_status",
-		"The application current health status.",
-		append(descAppDefaultLabels, "health_status"),
-		nil,
-	)
-)
+var descAppDefaultLabels = []string{"namespace", "name", "project"}
 
 // NewMetricsServer returns a new prometheus server which collects application metrics
-func NewMetricsServer(addr string, appLister applister.ApplicationLister, healthCheck func() error) *MetricsServer {
+func NewMetricsServer(addr string, appLister applister.ApplicationLister, healthCheck func() error, options ...MetricsServerOptions) *MetricsServer {
+	var opts MetricsServerOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+
+	droppedSeriesCounter := prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "argocd_metrics_series_dropped_total",
+		Help: "Number of metric series dropped to stay within metrics.max_series.",
+	})
+	guard := newCardinalityGuard(opts.Cardinality.MaxSeries, droppedSeriesCounter)
+	baseLabels := opts.Cardinality.projectedLabels()
+
 	mux := http.NewServeMux()
-	registry := NewAppRegistry(appLister)
-	mux.Handle(MetricsPath, promhttp.HandlerFor(prometheus.Gatherers{
+	registry := newAppRegistry(appLister, opts.Cardinality, guard)
+	registry.MustRegister(droppedSeriesCounter)
+	metricsHandler := promhttp.HandlerFor(prometheus.Gatherers{
 		// contains app controller specific metrics
 		registry,
 		// contains process, golang and controller workqueues metrics
 		prometheus.DefaultGatherer,
-	}, promhttp.HandlerOpts{}))
+	}, promhttp.HandlerOpts{})
+	handler, err := opts.secureHandler(metricsHandler)
+	if err != nil {
+		log.Fatalf("failed to configure metrics server security: %v", err)
+	}
+	mux.Handle(MetricsPath, handler)
+	tlsConfig, err := opts.tlsConfig()
+	if err != nil {
+		log.Fatalf("failed to configure metrics server TLS: %v", err)
+	}
+	// health checks are used by the kubelet and should not require authentication
 	healthz.ServeHealthCheck(mux, healthCheck)
 
 	syncCounter := prometheus.NewCounterVec(
@@ -80,7 +108,7 @@ func NewMetricsServer(addr string, appLister applister.ApplicationLister, health
 			Name: "argocd_app_sync_total",
 			Help: "Number of application syncs.",
 		},
-		append(descAppDefaultLabels, "phase"),
+		withLabels(baseLabels, "phase"),
 	)
 	registry.MustRegister(syncCounter)
 
@@ -89,7 +117,7 @@ func NewMetricsServer(addr string, appLister applister.ApplicationLister, health
 			Name: "argocd_app_k8s_request_total",
 			Help: "Number of kubernetes requests executed during application reconciliation.",
 		},
-		append(descAppDefaultLabels, "server", "response_code", "verb", "resource_kind", "resource_namespace"),
+		withLabels(baseLabels, "server", "response_code", "verb", "resource_kind", "resource_namespace"),
 	)
 	registry.MustRegister(k8sRequestCounter)
 
@@ -111,10 +139,32 @@ func NewMetricsServer(addr string, appLister applister.ApplicationLister, health
 			// Buckets chosen after observing a ~2100ms mean reconcile time
 			Buckets: []float64{0.25, .5, 1, 2, 4, 8, 16},
 		},
-		descAppDefaultLabels,
+		baseLabels,
 	)
 
 	registry.MustRegister(reconcileHistogram)
+
+	reconcilePhaseHistogram := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "argocd_app_reconcile_phase",
+			Help: "Application reconciliation phase performance.",
+			// Buckets chosen after observing a ~2100ms mean reconcile time
+			Buckets: []float64{0.25, .5, 1, 2, 4, 8, 16},
+		},
+		withLabels(baseLabels, "phase"),
+	)
+	registry.MustRegister(reconcilePhaseHistogram)
+
+	queueLatencyHistogram := prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "argocd_app_reconcile_queue_latency_seconds",
+			Help:    "Time an application spent waiting in the reconcile queue before reconciliation started.",
+			Buckets: []float64{0.25, .5, 1, 2, 4, 8, 16},
+		},
+		baseLabels,
+	)
+	registry.MustRegister(queueLatencyHistogram)
+
 	clusterEventsCounter := prometheus.NewCounterVec(prometheus.CounterOpts{
 		Name: "argocd_cluster_events_total",
 		Help: "Number of processes k8s resource events.",
@@ -124,14 +174,19 @@ func NewMetricsServer(addr string, appLister applister.ApplicationLister, health
 	return &MetricsServer{
 		registry: registry,
 		Server: &http.Server{
-			Addr:    addr,
-			Handler: mux,
+			Addr:      addr,
+			Handler:   mux,
+			TLSConfig: tlsConfig,
 		},
+		cardinality:             opts.Cardinality,
+		guard:                   guard,
 		syncCounter:             syncCounter,
 		k8sRequestCounter:       k8sRequestCounter,
 		kubectlExecCounter:      kubectlExecCounter,
 		kubectlExecPendingGauge: kubectlExecPendingGauge,
 		reconcileHistogram:      reconcileHistogram,
+		reconcilePhaseHistogram: reconcilePhaseHistogram,
+		queueLatencyHistogram:   queueLatencyHistogram,
 		clusterEventsCounter:    clusterEventsCounter,
 	}
 }
@@ -147,7 +202,9 @@ func (m *MetricsServer) IncSync(app *argoappv1.Application, state *argoappv1.Ope
 	if !state.Phase.Completed() {
 		return
 	}
-	m.syncCounter.WithLabelValues(app.Namespace, app.Name, app.Spec.GetProject(), string(state.Phase)).Inc()
+	values := withLabels(m.cardinality.projectValues([]string{app.Namespace, app.Name, app.Spec.GetProject()}), string(state.Phase))
+	m.guard.admit(seriesKey("sync", values), func() { m.syncCounter.DeleteLabelValues(values...) })
+	m.syncCounter.WithLabelValues(values...).Inc()
 }
 
 func (m *MetricsServer) IncKubectlExec(command string) {
@@ -175,52 +232,164 @@ func (m *MetricsServer) IncKubernetesRequest(app *argoappv1.Application, server,
 		name = app.Name
 		project = app.Spec.GetProject()
 	}
-	m.k8sRequestCounter.WithLabelValues(
-		namespace, name, project, server, statusCode,
-		verb, resourceKind, resourceNamespace,
-	).Inc()
+	values := withLabels(
+		m.cardinality.projectValues([]string{namespace, name, project}),
+		server, statusCode, verb, resourceKind, resourceNamespace,
+	)
+	agg := m.aggregator
+	m.guard.admit(seriesKey("k8s_request", values), func() {
+		m.k8sRequestCounter.DeleteLabelValues(values...)
+		if agg != nil {
+			agg.evict(namespace, name, project)
+		}
+	})
+	m.k8sRequestCounter.WithLabelValues(values...).Inc()
+	if agg != nil {
+		agg.observeK8sRequest(namespace, name, project)
+	}
 }
 
 // IncReconcile increments the reconcile counter for an application
 func (m *MetricsServer) IncReconcile(app *argoappv1.Application, duration time.Duration) {
-	m.reconcileHistogram.WithLabelValues(app.Namespace, app.Name, app.Spec.GetProject()).Observe(duration.Seconds())
+	namespace, name, project := app.Namespace, app.Name, app.Spec.GetProject()
+	values := m.cardinality.projectValues([]string{namespace, name, project})
+	agg := m.aggregator
+	m.guard.admit(seriesKey("reconcile", values), func() {
+		m.reconcileHistogram.DeleteLabelValues(values...)
+		if agg != nil {
+			agg.evict(namespace, name, project)
+		}
+	})
+	m.reconcileHistogram.WithLabelValues(values...).Observe(duration.Seconds())
+	if agg != nil {
+		agg.observeReconcile(namespace, name, project, duration.Seconds())
+	}
+}
+
+// ObserveQueueLatency records how long an application sat in the reconcile queue before
+// reconciliation started, given the time it was enqueued.
+func (m *MetricsServer) ObserveQueueLatency(app *argoappv1.Application, enqueuedAt time.Time) {
+	values := m.cardinality.projectValues([]string{app.Namespace, app.Name, app.Spec.GetProject()})
+	m.guard.admit(seriesKey("queue_latency", values), func() { m.queueLatencyHistogram.DeleteLabelValues(values...) })
+	m.queueLatencyHistogram.WithLabelValues(values...).Observe(time.Since(enqueuedAt).Seconds())
+}
+
+// StartReconcile begins timing a reconciliation of app and returns a ReconcileTimer used to
+// record the duration of each sub-phase as well as the overall reconcile time.
+func (m *MetricsServer) StartReconcile(app *argoappv1.Application) ReconcileHandle {
+	now := time.Now()
+	return &ReconcileTimer{
+		metrics:        m,
+		app:            app,
+		started:        now,
+		lastCheckpoint: now,
+	}
+}
+
+// ObservePhase records the duration since the timer started or the last call to ObservePhase,
+// attributing it to the named reconcile phase.
+func (t *ReconcileTimer) ObservePhase(phase reconcilePhase) {
+	now := time.Now()
+	m := t.metrics
+	values := withLabels(m.cardinality.projectValues([]string{t.app.Namespace, t.app.Name, t.app.Spec.GetProject()}), string(phase))
+	m.guard.admit(seriesKey("reconcile_phase", values), func() { m.reconcilePhaseHistogram.DeleteLabelValues(values...) })
+	m.reconcilePhaseHistogram.WithLabelValues(values...).Observe(now.Sub(t.lastCheckpoint).Seconds())
+	t.lastCheckpoint = now
+}
+
+// Finish records the total reconciliation duration since the timer started into the overall
+// argocd_app_reconcile histogram.
+func (t *ReconcileTimer) Finish() {
+	t.metrics.IncReconcile(t.app, time.Since(t.started))
 }
 
 type appCollector struct {
 	store applister.ApplicationLister
+	cfg   CardinalityConfig
+	guard *cardinalityGuard
+
+	descAppInfo           *prometheus.Desc
+	descAppCreated        *prometheus.Desc
+	descAppSyncStatusCode *prometheus.Desc
+	descAppHealthStatus   *prometheus.Desc
 }
 
-// NewAppCollector returns a prometheus collector for application metrics
-func NewAppCollector(appLister applister.ApplicationLister) prometheus.Collector {
+// newAppCollector returns a prometheus collector for application metrics. cfg and guard bound
+// the label set and total series emitted; pass a zero CardinalityConfig and a disabled guard
+// (max series <= 0) to preserve unbounded cardinality.
+func newAppCollector(appLister applister.ApplicationLister, cfg CardinalityConfig, guard *cardinalityGuard) prometheus.Collector {
+	baseLabels := cfg.projectedLabels()
 	return &appCollector{
 		store: appLister,
+		cfg:   cfg,
+		guard: guard,
+
+		descAppInfo: prometheus.NewDesc(
+			"argocd_app_info",
+			"Information about application.",
+			withLabels(baseLabels, "repo", "dest_server", "dest_namespace"),
+			nil,
+		),
+		descAppCreated: prometheus.NewDesc(
+			"argocd_app_created_time",
+			"Creation time in unix timestamp for an application.",
+			baseLabels,
+			nil,
+		),
+		descAppSyncStatusCode: prometheus.NewDesc(
+			"argocd_app_sync_status",
+			"The application current sync status.",
+			withLabels(baseLabels, "sync_status"),
+			nil,
+		),
+		descAppHealthStatus: prometheus.NewDesc(
+			"argocd_app_health_status",
+			"The application current health status.",
+			withLabels(baseLabels, "health_status"),
+			nil,
+		),
 	}
 }
 
-// NewAppRegistry creates a new prometheus registry that collects applications
-func NewAppRegistry(appLister applister.ApplicationLister) *prometheus.Registry {
+// newAppRegistry creates a new prometheus registry that collects applications
+func newAppRegistry(appLister applister.ApplicationLister, cfg CardinalityConfig, guard *cardinalityGuard) *prometheus.Registry {
 	registry := prometheus.NewRegistry()
-	registry.MustRegister(NewAppCollector(appLister))
+	registry.MustRegister(newAppCollector(appLister, cfg, guard))
 	return registry
 }
 
 // Describe implements the prometheus.Collector interface
 func (c *appCollector) Describe(ch chan<- *prometheus.Desc) {
-	ch <- descAppInfo
-	ch <- descAppCreated
-	ch <- descAppSyncStatusCode
-	ch <- descAppHealthStatus
+	ch <- c.descAppInfo
+	ch <- c.descAppCreated
+	ch <- c.descAppSyncStatusCode
+	ch <- c.descAppHealthStatus
 }
 
-// Collect implements the prometheus.Collector interface
+// Collect implements the prometheus.Collector interface. Apps are visited in a stable order
+// so that, when more distinct label tuples exist than c.guard allows, the same tuples are
+// admitted from one scrape to the next rather than shifting with map iteration order.
 func (c *appCollector) Collect(ch chan<- prometheus.Metric) {
 	apps, err := c.store.List(labels.NewSelector())
 	if err != nil {
 		log.Warnf("Failed to collect applications: %v", err)
 		return
 	}
-	for _, app := range apps {
-		collectApps(ch, app)
+	sort.Slice(apps, func(i, j int) bool {
+		return apps[i].Namespace+"/"+apps[i].Name < apps[j].Namespace+"/"+apps[j].Name
+	})
+
+	keys := make([]string, len(apps))
+	for i, app := range apps {
+		values := c.cfg.projectValues([]string{app.Namespace, app.Name, app.Spec.GetProject()})
+		keys[i] = seriesKey("app", values)
+		c.guard.admit(keys[i], nil)
+	}
+	for i, app := range apps {
+		if !c.guard.isTracked(keys[i]) {
+			continue
+		}
+		c.collectApp(ch, app)
 	}
 }
 
@@ -231,30 +400,30 @@ func boolFloat64(b bool) float64 {
 	return 0
 }
 
-func collectApps(ch chan<- prometheus.Metric, app *argoappv1.Application) {
+func (c *appCollector) collectApp(ch chan<- prometheus.Metric, app *argoappv1.Application) {
 	addConstMetric := func(desc *prometheus.Desc, t prometheus.ValueType, v float64, lv ...string) {
 		project := app.Spec.GetProject()
-		lv = append([]string{app.Namespace, app.Name, project}, lv...)
+		lv = withLabels(c.cfg.projectValues([]string{app.Namespace, app.Name, project}), lv...)
 		ch <- prometheus.MustNewConstMetric(desc, t, v, lv...)
 	}
 	addGauge := func(desc *prometheus.Desc, v float64, lv ...string) {
 		addConstMetric(desc, prometheus.GaugeValue, v, lv...)
 	}
 
-	addGauge(descAppInfo, 1, git.NormalizeGitURL(app.Spec.Source.RepoURL), app.Spec.Destination.Server, app.Spec.Destination.Namespace)
+	addGauge(c.descAppInfo, 1, git.NormalizeGitURL(app.Spec.Source.RepoURL), app.Spec.Destination.Server, app.Spec.Destination.Namespace)
 
-	addGauge(descAppCreated, float64(app.CreationTimestamp.Unix()))
+	addGauge(c.descAppCreated, float64(app.CreationTimestamp.Unix()))
 
 	syncStatus := app.Status.Sync.Status
-	addGauge(descAppSyncStatusCode, boolFloat64(syncStatus == argoappv1.SyncStatusCodeSynced), string(argoappv1.SyncStatusCodeSynced))
-	addGauge(descAppSyncStatusCode, boolFloat64(syncStatus == argoappv1.SyncStatusCodeOutOfSync), string(argoappv1.SyncStatusCodeOutOfSync))
-	addGauge(descAppSyncStatusCode, boolFloat64(syncStatus == argoappv1.SyncStatusCodeUnknown || syncStatus == ""), string(argoappv1.SyncStatusCodeUnknown))
+	addGauge(c.descAppSyncStatusCode, boolFloat64(syncStatus == argoappv1.SyncStatusCodeSynced), string(argoappv1.SyncStatusCodeSynced))
+	addGauge(c.descAppSyncStatusCode, boolFloat64(syncStatus == argoappv1.SyncStatusCodeOutOfSync), string(argoappv1.SyncStatusCodeOutOfSync))
+	addGauge(c.descAppSyncStatusCode, boolFloat64(syncStatus == argoappv1.SyncStatusCodeUnknown || syncStatus == ""), string(argoappv1.SyncStatusCodeUnknown))
 
 	healthStatus := app.Status.Health.Status
-	addGauge(descAppHealthStatus, boolFloat64(healthStatus == argoappv1.HealthStatusUnknown || healthStatus == ""), argoappv1.HealthStatusUnknown)
-	addGauge(descAppHealthStatus, boolFloat64(healthStatus == argoappv1.HealthStatusProgressing), argoappv1.HealthStatusProgressing)
-	addGauge(descAppHealthStatus, boolFloat64(healthStatus == argoappv1.HealthStatusSuspended), argoappv1.HealthStatusSuspended)
-	addGauge(descAppHealthStatus, boolFloat64(healthStatus == argoappv1.HealthStatusHealthy), argoappv1.HealthStatusHealthy)
-	addGauge(descAppHealthStatus, boolFloat64(healthStatus == argoappv1.HealthStatusDegraded), argoappv1.HealthStatusDegraded)
-	addGauge(descAppHealthStatus, boolFloat64(healthStatus == argoappv1.HealthStatusMissing), argoappv1.HealthStatusMissing)
+	addGauge(c.descAppHealthStatus, boolFloat64(healthStatus == argoappv1.HealthStatusUnknown || healthStatus == ""), argoappv1.HealthStatusUnknown)
+	addGauge(c.descAppHealthStatus, boolFloat64(healthStatus == argoappv1.HealthStatusProgressing), argoappv1.HealthStatusProgressing)
+	addGauge(c.descAppHealthStatus, boolFloat64(healthStatus == argoappv1.HealthStatusSuspended), argoappv1.HealthStatusSuspended)
+	addGauge(c.descAppHealthStatus, boolFloat64(healthStatus == argoappv1.HealthStatusHealthy), argoappv1.HealthStatusHealthy)
+	addGauge(c.descAppHealthStatus, boolFloat64(healthStatus == argoappv1.HealthStatusDegraded), argoappv1.HealthStatusDegraded)
+	addGauge(c.descAppHealthStatus, boolFloat64(healthStatus == argoappv1.HealthStatusMissing), argoappv1.HealthStatusMissing)
 }
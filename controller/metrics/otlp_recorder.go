@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/metric/instrument"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+
+	argoappv1 "github.com/argoproj/argo-cd/pkg/apis/application/v1alpha1"
+)
+
+// otlpPushInterval is how often accumulated metrics are pushed to the OTLP collector.
+const otlpPushInterval = 15 * time.Second
+
+// otlpRecorder is a Recorder that pushes metrics to an OTLP/HTTP collector on a periodic
+// interval, for operators whose observability stack has no Prometheus scraper.
+type otlpRecorder struct {
+	provider          *sdkmetric.MeterProvider
+	syncCounter       instrument.Float64Counter
+	k8sRequestCounter instrument.Float64Counter
+	reconcileDuration instrument.Float64Histogram
+	queueLatency      instrument.Float64Histogram
+	clusterEvents     instrument.Float64Counter
+	kubectlExec       instrument.Float64Counter
+}
+
+func newOTLPRecorder(endpoint string) (*otlpRecorder, error) {
+	exporter, err := otlpmetrichttp.New(context.Background(), otlpmetrichttp.WithEndpoint(endpoint), otlpmetrichttp.WithInsecure())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otlp exporter: %w", err)
+	}
+	provider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter, sdkmetric.WithInterval(otlpPushInterval))),
+	)
+	meter := provider.Meter("argocd.app.controller")
+
+	r := &otlpRecorder{provider: provider}
+	if r.syncCounter, err = meter.Float64Counter("argocd_app_sync_total"); err != nil {
+		return nil, err
+	}
+	if r.k8sRequestCounter, err = meter.Float64Counter("argocd_app_k8s_request_total"); err != nil {
+		return nil, err
+	}
+	if r.reconcileDuration, err = meter.Float64Histogram("argocd_app_reconcile"); err != nil {
+		return nil, err
+	}
+	if r.queueLatency, err = meter.Float64Histogram("argocd_app_reconcile_queue_latency_seconds"); err != nil {
+		return nil, err
+	}
+	if r.clusterEvents, err = meter.Float64Counter("argocd_cluster_events_total"); err != nil {
+		return nil, err
+	}
+	if r.kubectlExec, err = meter.Float64Counter("argocd_kubectl_exec_total"); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func appAttrs(app *argoappv1.Application, extra ...attribute.KeyValue) []attribute.KeyValue {
+	var namespace, name, project string
+	if app != nil {
+		namespace, name, project = app.Namespace, app.Name, app.Spec.GetProject()
+	}
+	return append([]attribute.KeyValue{
+		attribute.String("namespace", namespace),
+		attribute.String("name", name),
+		attribute.String("project", project),
+	}, extra...)
+}
+
+func (o *otlpRecorder) IncSync(app *argoappv1.Application, state *argoappv1.OperationState) {
+	if !state.Phase.Completed() {
+		return
+	}
+	o.syncCounter.Add(context.Background(), 1, appAttrs(app, attribute.String("phase", string(state.Phase)))...)
+}
+
+// IncKubectlExec is recorded as a counter so kubectl activity is visible over OTLP. The
+// pending gauge pair below is not exported over OTLP; both methods are no-ops.
+func (o *otlpRecorder) IncKubectlExec(command string) {
+	o.kubectlExec.Add(context.Background(), 1, attribute.String("command", command))
+}
+
+func (o *otlpRecorder) IncKubectlExecPending(command string) {}
+
+func (o *otlpRecorder) DecKubectlExecPending(command string) {}
+
+func (o *otlpRecorder) IncClusterEventsCount(server, group, kind string) {
+	o.clusterEvents.Add(context.Background(), 1,
+		attribute.String("server", server), attribute.String("group", group), attribute.String("kind", kind))
+}
+
+func (o *otlpRecorder) IncKubernetesRequest(app *argoappv1.Application, server, statusCode, verb, resourceKind, resourceNamespace string) {
+	o.k8sRequestCounter.Add(context.Background(), 1, appAttrs(app,
+		attribute.String("server", server),
+		attribute.String("response_code", statusCode),
+		attribute.String("verb", verb),
+		attribute.String("resource_kind", resourceKind),
+		attribute.String("resource_namespace", resourceNamespace),
+	)...)
+}
+
+func (o *otlpRecorder) IncReconcile(app *argoappv1.Application, duration time.Duration) {
+	o.reconcileDuration.Record(context.Background(), duration.Seconds(), appAttrs(app)...)
+}
+
+func (o *otlpRecorder) ObserveQueueLatency(app *argoappv1.Application, enqueuedAt time.Time) {
+	o.queueLatency.Record(context.Background(), time.Since(enqueuedAt).Seconds(), appAttrs(app)...)
+}
+
+// StartReconcile has no per-phase breakdown over OTLP yet; it reports only the overall duration
+// on Finish.
+func (o *otlpRecorder) StartReconcile(app *argoappv1.Application) ReconcileHandle {
+	return newSimpleReconcileHandle(func(d time.Duration) { o.IncReconcile(app, d) })
+}
@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"fmt"
+	"time"
+
+	argoappv1 "github.com/argoproj/argo-cd/pkg/apis/application/v1alpha1"
+	applister "github.com/argoproj/argo-cd/pkg/client/listers/application/v1alpha1"
+)
+
+// Recorder is the set of application controller metrics recording operations that must be
+// supported by any metrics backend. MetricsServer (Prometheus) is the default implementation;
+// NewRecorder selects an alternate backend based on MetricsConfig.Protocol so that call sites
+// elsewhere in the controller never need to know which backend is in use.
+type Recorder interface {
+	IncSync(app *argoappv1.Application, state *argoappv1.OperationState)
+	IncKubectlExec(command string)
+	IncKubectlExecPending(command string)
+	DecKubectlExecPending(command string)
+	IncClusterEventsCount(server, group, kind string)
+	IncKubernetesRequest(app *argoappv1.Application, server, statusCode, verb, resourceKind, resourceNamespace string)
+	IncReconcile(app *argoappv1.Application, duration time.Duration)
+	ObserveQueueLatency(app *argoappv1.Application, enqueuedAt time.Time)
+	// StartReconcile begins timing a reconciliation of app, with the clock starting at the
+	// call to StartReconcile itself so the reported duration is always real regardless of
+	// backend. Callers must call Finish exactly once; ObservePhase is a no-op on backends that
+	// don't support a per-phase breakdown.
+	StartReconcile(app *argoappv1.Application) ReconcileHandle
+}
+
+// ReconcileHandle times a single in-flight application reconciliation.
+type ReconcileHandle interface {
+	ObservePhase(phase reconcilePhase)
+	Finish()
+}
+
+// simpleReconcileHandle times the overall reconciliation and reports it through record on
+// Finish; it is used by backends with no per-phase breakdown, so ObservePhase is a no-op.
+type simpleReconcileHandle struct {
+	record  func(time.Duration)
+	started time.Time
+}
+
+func newSimpleReconcileHandle(record func(time.Duration)) *simpleReconcileHandle {
+	return &simpleReconcileHandle{record: record, started: time.Now()}
+}
+
+func (h *simpleReconcileHandle) ObservePhase(reconcilePhase) {}
+
+func (h *simpleReconcileHandle) Finish() {
+	h.record(time.Since(h.started))
+}
+
+// MetricsProtocol identifies which backend a Recorder should push/export metrics through.
+type MetricsProtocol string
+
+const (
+	MetricsProtocolPrometheus MetricsProtocol = "prometheus"
+	MetricsProtocolOTLP       MetricsProtocol = "otlp"
+	MetricsProtocolStatsD     MetricsProtocol = "statsd"
+)
+
+// MetricsConfig holds the settings read from argocd-cm (metrics.protocol, metrics.otlp.endpoint,
+// metrics.statsd.addr) that determine which Recorder backend NewRecorder constructs.
+type MetricsConfig struct {
+	// Protocol selects the recording backend. Defaults to MetricsProtocolPrometheus when empty.
+	Protocol MetricsProtocol
+	// OTLPEndpoint is the OTLP/HTTP collector endpoint used when Protocol is otlp.
+	OTLPEndpoint string
+	// StatsDAddr is the host:port of the StatsD daemon used when Protocol is statsd.
+	StatsDAddr string
+}
+
+// NewRecorder constructs the Recorder backend selected by cfg.Protocol. The Prometheus backend
+// also serves the /metrics endpoint on addr, since it is pull-based; the OTLP and StatsD
+// backends push metrics and do not bind a listener.
+func NewRecorder(cfg MetricsConfig, addr string, appLister applister.ApplicationLister, healthCheck func() error) (Recorder, error) {
+	switch cfg.Protocol {
+	case "", MetricsProtocolPrometheus:
+		return NewMetricsServer(addr, appLister, healthCheck), nil
+	case MetricsProtocolOTLP:
+		return newOTLPRecorder(cfg.OTLPEndpoint)
+	case MetricsProtocolStatsD:
+		return newStatsDRecorder(cfg.StatsDAddr)
+	default:
+		return nil, fmt.Errorf("unsupported metrics protocol: %s", cfg.Protocol)
+	}
+}
@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewRecorderDefaultsToPrometheus(t *testing.T) {
+	recorder, err := NewRecorder(MetricsConfig{}, ":0", nil, func() error { return nil })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := recorder.(*MetricsServer); !ok {
+		t.Errorf("expected the Prometheus MetricsServer by default, got %T", recorder)
+	}
+}
+
+func TestNewRecorderRejectsUnknownProtocol(t *testing.T) {
+	_, err := NewRecorder(MetricsConfig{Protocol: "carrier-pigeon"}, ":0", nil, func() error { return nil })
+	if err == nil {
+		t.Fatal("expected an unsupported protocol to be rejected")
+	}
+}
+
+func TestSimpleReconcileHandleReportsRealDuration(t *testing.T) {
+	var got time.Duration
+	handle := newSimpleReconcileHandle(func(d time.Duration) { got = d })
+
+	time.Sleep(5 * time.Millisecond)
+	handle.Finish()
+
+	if got < 5*time.Millisecond {
+		t.Errorf("expected Finish to report at least the elapsed sleep, got %v", got)
+	}
+}
+
+func TestSimpleReconcileHandleObservePhaseIsANoOp(t *testing.T) {
+	called := false
+	handle := newSimpleReconcileHandle(func(time.Duration) { called = true })
+
+	handle.ObservePhase(ReconcilePhaseGitFetch)
+	if called {
+		t.Error("expected ObservePhase to never invoke the record callback")
+	}
+}
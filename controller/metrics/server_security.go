@@ -0,0 +1,146 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// MetricsServerOptions hardens the /metrics endpoint constructed by NewMetricsServer. All
+// fields are optional; an empty MetricsServerOptions preserves the previous unauthenticated,
+// plaintext behavior. The health check endpoint is never covered by these options.
+type MetricsServerOptions struct {
+	// TLSCertFile and TLSKeyFile serve /metrics over HTTPS when both are set.
+	TLSCertFile string
+	TLSKeyFile  string
+	// RequireClientCert requests and requires a client certificate during the TLS handshake,
+	// verified against ClientCAFile.
+	RequireClientCert bool
+	// ClientCAFile is the PEM bundle of CAs trusted to sign client certificates when
+	// RequireClientCert is set. Required whenever RequireClientCert is set, since without it
+	// client certificates are verified against the system root pool and every certificate
+	// issued by a private CA is rejected.
+	ClientCAFile string
+	// AuthTokenFile, when set, requires requests to present it as a bearer token.
+	AuthTokenFile string
+	// AllowedCIDRs, when non-empty, restricts /metrics to clients whose address falls within
+	// one of the listed CIDR blocks.
+	AllowedCIDRs []string
+	// Cardinality bounds the number of distinct application label tuples tracked across the
+	// per-app metrics; see CardinalityConfig.
+	Cardinality CardinalityConfig
+}
+
+func (o MetricsServerOptions) tlsConfig() (*tls.Config, error) {
+	if o.TLSCertFile == "" || o.TLSKeyFile == "" {
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(o.TLSCertFile, o.TLSKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load metrics server TLS cert/key: %w", err)
+	}
+	cfg := &tls.Config{Certificates: []tls.Certificate{cert}}
+	if o.RequireClientCert {
+		if o.ClientCAFile == "" {
+			return nil, fmt.Errorf("metrics server RequireClientCert is set but ClientCAFile is empty")
+		}
+		pool, err := loadCertPool(o.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load metrics server client CA bundle: %w", err)
+		}
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		cfg.ClientCAs = pool
+	}
+	return cfg, nil
+}
+
+func loadCertPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("%q contains no valid PEM certificates", path)
+	}
+	return pool, nil
+}
+
+// secureHandler wraps next with middleware enforcing the bearer-token and CIDR allow-list
+// checks configured in o. It is applied only to the /metrics handler; the health check
+// remains unauthenticated.
+func (o MetricsServerOptions) secureHandler(next http.Handler) (http.Handler, error) {
+	var token string
+	if o.AuthTokenFile != "" {
+		b, err := os.ReadFile(o.AuthTokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read metrics auth token file: %w", err)
+		}
+		token = strings.TrimSpace(string(b))
+		if token == "" {
+			return nil, fmt.Errorf("metrics auth token file %q is empty", o.AuthTokenFile)
+		}
+	}
+
+	var allowed []*net.IPNet
+	for _, cidr := range o.AllowedCIDRs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid metrics allowed CIDR %q: %w", cidr, err)
+		}
+		allowed = append(allowed, ipNet)
+	}
+
+	if token == "" && len(allowed) == 0 {
+		return next, nil
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token != "" {
+			const prefix = "Bearer "
+			header := r.Header.Get("Authorization")
+			if !strings.HasPrefix(header, prefix) || subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(header, prefix)), []byte(token)) != 1 {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		if len(allowed) > 0 {
+			if !clientAllowed(r.RemoteAddr, allowed) {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	}), nil
+}
+
+func clientAllowed(remoteAddr string, allowed []*net.IPNet) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range allowed {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ListenAndServe serves /metrics over TLS when MetricsServerOptions configured a certificate,
+// otherwise it falls back to plaintext HTTP.
+func (m *MetricsServer) ListenAndServe() error {
+	if m.Server.TLSConfig != nil {
+		return m.Server.ListenAndServeTLS("", "")
+	}
+	return m.Server.ListenAndServe()
+}
@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTokenFile(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatalf("failed to write token file: %v", err)
+	}
+	return path
+}
+
+func TestSecureHandlerNoOptionsServesUnauthenticated(t *testing.T) {
+	opts := MetricsServerOptions{}
+	handler, err := opts.secureHandler(http.NotFoundHandler())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected the wrapped handler to run unauthenticated, got status %d", rr.Code)
+	}
+}
+
+func TestSecureHandlerEmptyTokenFileIsConfigError(t *testing.T) {
+	opts := MetricsServerOptions{AuthTokenFile: writeTokenFile(t, "   \n")}
+	if _, err := opts.secureHandler(http.NotFoundHandler()); err == nil {
+		t.Fatal("expected an empty auth token file to be rejected as a config error")
+	}
+}
+
+func TestSecureHandlerRequiresBearerToken(t *testing.T) {
+	opts := MetricsServerOptions{AuthTokenFile: writeTokenFile(t, "s3cr3t")}
+	handler, err := opts.secureHandler(http.NotFoundHandler())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected missing token to be rejected, got status %d", rr.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer s3cr3t")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected a valid token to reach the wrapped handler, got status %d", rr.Code)
+	}
+}
+
+func TestSecureHandlerRejectsDisallowedCIDR(t *testing.T) {
+	opts := MetricsServerOptions{AllowedCIDRs: []string{"10.0.0.0/8"}}
+	handler, err := opts.secureHandler(http.NotFoundHandler())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.RemoteAddr = "192.168.1.1:12345"
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("expected a client outside the allow-list to be rejected, got status %d", rr.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.RemoteAddr = "10.1.2.3:12345"
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected a client inside the allow-list to reach the wrapped handler, got status %d", rr.Code)
+	}
+}
@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// generateTestCertKeyPair writes a self-signed PEM cert/key pair to t.TempDir() and returns
+// their paths, along with the cert's raw DER bytes so callers can build a CA pool from it.
+func generateTestCertKeyPair(t *testing.T, commonName string) (certFile, keyFile string, certDER []byte) {
+	t.Helper()
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Unix(0, 0),
+		NotAfter:              time.Unix(0, 0).Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &priv.PublicKey, priv)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(priv)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("failed to write cert file: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("failed to write key file: %v", err)
+	}
+	return certFile, keyFile, der
+}
+
+func TestTLSConfigNoCertFilesReturnsNil(t *testing.T) {
+	cfg, err := MetricsServerOptions{}.tlsConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg != nil {
+		t.Errorf("expected a nil TLS config when no cert/key is configured, got %+v", cfg)
+	}
+}
+
+func TestTLSConfigLoadsServerCertificate(t *testing.T) {
+	certFile, keyFile, _ := generateTestCertKeyPair(t, "metrics-server")
+	opts := MetricsServerOptions{TLSCertFile: certFile, TLSKeyFile: keyFile}
+
+	cfg, err := opts.tlsConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.Certificates) != 1 {
+		t.Fatalf("expected exactly one server certificate, got %d", len(cfg.Certificates))
+	}
+}
+
+func TestTLSConfigRequireClientCertWithoutCAFileIsConfigError(t *testing.T) {
+	certFile, keyFile, _ := generateTestCertKeyPair(t, "metrics-server")
+	opts := MetricsServerOptions{TLSCertFile: certFile, TLSKeyFile: keyFile, RequireClientCert: true}
+
+	if _, err := opts.tlsConfig(); err == nil {
+		t.Fatal("expected RequireClientCert without ClientCAFile to be a config error")
+	}
+}
+
+func TestTLSConfigRequireClientCertPopulatesClientCAs(t *testing.T) {
+	certFile, keyFile, _ := generateTestCertKeyPair(t, "metrics-server")
+	caCertFile, _, caDER := generateTestCertKeyPair(t, "test-client-ca")
+	opts := MetricsServerOptions{
+		TLSCertFile:       certFile,
+		TLSKeyFile:        keyFile,
+		RequireClientCert: true,
+		ClientCAFile:      caCertFile,
+	}
+
+	cfg, err := opts.tlsConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.ClientAuth != tls.RequireAndVerifyClientCert {
+		t.Errorf("expected ClientAuth to require and verify client certs, got %v", cfg.ClientAuth)
+	}
+	if cfg.ClientCAs == nil {
+		t.Fatal("expected ClientCAs to be populated")
+	}
+
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatalf("failed to parse generated CA cert: %v", err)
+	}
+	// The generated certificates are only valid for the day after the Unix epoch.
+	verifyAt := time.Unix(0, 0).Add(time.Hour)
+	if _, err := caCert.Verify(x509.VerifyOptions{Roots: cfg.ClientCAs, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}, CurrentTime: verifyAt}); err != nil {
+		t.Errorf("expected the configured CA to verify against cfg.ClientCAs: %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	argoappv1 "github.com/argoproj/argo-cd/pkg/apis/application/v1alpha1"
+)
+
+// statsdRecorder is a Recorder that pushes metrics to a StatsD daemon over UDP using the
+// Datadog tag extension (name:value|type|#tag1:val1,tag2:val2) so that application labels
+// are preserved without needing per-label metric names.
+type statsdRecorder struct {
+	conn net.Conn
+}
+
+func newStatsDRecorder(addr string) (*statsdRecorder, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial statsd at %s: %w", addr, err)
+	}
+	return &statsdRecorder{conn: conn}, nil
+}
+
+func (s *statsdRecorder) send(line string) {
+	// StatsD is fire-and-forget over UDP; a send failure here is not actionable and should
+	// not be allowed to affect reconciliation, so it is silently dropped.
+	_, _ = s.conn.Write([]byte(line))
+}
+
+func appTags(app *argoappv1.Application, extra ...string) string {
+	var namespace, name, project string
+	if app != nil {
+		namespace, name, project = app.Namespace, app.Name, app.Spec.GetProject()
+	}
+	tags := fmt.Sprintf("namespace:%s,name:%s,project:%s", namespace, name, project)
+	for _, e := range extra {
+		tags += "," + e
+	}
+	return tags
+}
+
+func (s *statsdRecorder) IncSync(app *argoappv1.Application, state *argoappv1.OperationState) {
+	if !state.Phase.Completed() {
+		return
+	}
+	s.send(fmt.Sprintf("argocd.app.sync_total:1|c|#%s", appTags(app, "phase:"+string(state.Phase))))
+}
+
+func (s *statsdRecorder) IncKubectlExec(command string) {
+	s.send(fmt.Sprintf("argocd.kubectl.exec_total:1|c|#command:%s", command))
+}
+
+func (s *statsdRecorder) IncKubectlExecPending(command string) {
+	s.send(fmt.Sprintf("argocd.kubectl.exec_pending:+1|g|#command:%s", command))
+}
+
+func (s *statsdRecorder) DecKubectlExecPending(command string) {
+	s.send(fmt.Sprintf("argocd.kubectl.exec_pending:-1|g|#command:%s", command))
+}
+
+func (s *statsdRecorder) IncClusterEventsCount(server, group, kind string) {
+	s.send(fmt.Sprintf("argocd.cluster.events_total:1|c|#server:%s,group:%s,kind:%s", server, group, kind))
+}
+
+func (s *statsdRecorder) IncKubernetesRequest(app *argoappv1.Application, server, statusCode, verb, resourceKind, resourceNamespace string) {
+	tags := appTags(app, "server:"+server, "response_code:"+statusCode, "verb:"+verb, "resource_kind:"+resourceKind, "resource_namespace:"+resourceNamespace)
+	s.send(fmt.Sprintf("argocd.app.k8s_request_total:1|c|#%s", tags))
+}
+
+func (s *statsdRecorder) IncReconcile(app *argoappv1.Application, duration time.Duration) {
+	s.send(fmt.Sprintf("argocd.app.reconcile:%f|h|#%s", duration.Seconds(), appTags(app)))
+}
+
+func (s *statsdRecorder) ObserveQueueLatency(app *argoappv1.Application, enqueuedAt time.Time) {
+	s.send(fmt.Sprintf("argocd.app.reconcile_queue_latency_seconds:%f|h|#%s", time.Since(enqueuedAt).Seconds(), appTags(app)))
+}
+
+// StartReconcile has no per-phase breakdown over StatsD; it reports only the overall duration
+// on Finish.
+func (s *statsdRecorder) StartReconcile(app *argoappv1.Application) ReconcileHandle {
+	return newSimpleReconcileHandle(func(d time.Duration) { s.IncReconcile(app, d) })
+}
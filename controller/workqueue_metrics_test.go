@@ -0,0 +1,27 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/argoproj/argo-cd/controller/metrics"
+)
+
+// TestApplicationControllerWorkqueueDepthMoves guards against registering the workqueue metrics
+// provider after the workqueue is constructed: client-go snapshots the provider at
+// construction time, so registering afterwards leaves the queue permanently wired to the no-op
+// default and argocd_workqueue_depth would stay at its pre-touched zero value forever.
+func TestApplicationControllerWorkqueueDepthMoves(t *testing.T) {
+	ctrl, err := NewApplicationController(newFakeAppLister(), ":0", func() error { return nil }, metrics.MetricsConfig{}, nil)
+	if err != nil {
+		t.Fatalf("failed to construct controller: %v", err)
+	}
+
+	ctrl.appQueue.Add("default/guestbook")
+
+	body := scrapeMetrics(t, ctrl)
+	const metric = `argocd_workqueue_depth{name="application"} 1`
+	if !strings.Contains(body, metric) {
+		t.Errorf("expected %q after adding one item to the queue, got:\n%s", metric, body)
+	}
+}